@@ -0,0 +1,171 @@
+// Package auth provides password hashing and JWT issuing/verification for
+// the API, plus HTTP middleware that enforces authentication and roles.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// contextKey is an unexported type so values stored by this package never
+// collide with keys set by other packages.
+type contextKey string
+
+const userContextKey contextKey = "authUser"
+
+// Claims is the JWT payload issued on login/register.
+type Claims struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// User is the authenticated identity attached to the request context.
+type User struct {
+	ID    int
+	Email string
+	Role  string
+}
+
+var ErrNoToken = errors.New("auth: no bearer token provided")
+var ErrInvalidToken = errors.New("auth: invalid or expired token")
+var ErrMissingSecret = errors.New("auth: JWT_SECRET environment variable must be set")
+
+func secret() ([]byte, error) {
+	s := os.Getenv("JWT_SECRET")
+	if s == "" {
+		return nil, ErrMissingSecret
+	}
+	return []byte(s), nil
+}
+
+// ValidateConfig checks that the environment this package depends on
+// (JWT_SECRET) is present. Call it once at startup, before serving any
+// requests, so misconfiguration fails fast instead of surfacing mid-request.
+func ValidateConfig() error {
+	_, err := secret()
+	return err
+}
+
+// HashPassword hashes a plaintext password with bcrypt for storage.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword compares a plaintext password against its bcrypt hash.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// IssueToken signs a JWT for the given user, valid for 24 hours.
+func IssueToken(userID int, email, role string) (string, error) {
+	claims := Claims{
+		UserID: userID,
+		Email:  email,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+		},
+	}
+
+	secretBytes, err := secret()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secretBytes)
+}
+
+// ParseToken verifies a signed JWT and returns its claims. It returns
+// ErrMissingSecret if JWT_SECRET isn't configured, and ErrInvalidToken for
+// any other failure to verify.
+func ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return secret()
+	})
+	if err != nil {
+		if errors.Is(err, ErrMissingSecret) {
+			return nil, ErrMissingSecret
+		}
+		return nil, ErrInvalidToken
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrNoToken
+	}
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", ErrNoToken
+	}
+	return parts[1], nil
+}
+
+// RequireAuth rejects requests without a valid bearer token and stores the
+// authenticated user on the request context for downstream handlers.
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, err := bearerToken(r)
+		if err != nil {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := ParseToken(tokenString)
+		if errors.Is(err, ErrMissingSecret) {
+			http.Error(w, `{"error":"internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+
+		user := &User{ID: claims.UserID, Email: claims.Email, Role: claims.Role}
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireRole builds middleware that additionally rejects requests whose
+// authenticated user does not hold the given role. It must be chained after
+// RequireAuth.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok || user.Role != role {
+				http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserFromContext returns the authenticated user stored by RequireAuth.
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userContextKey).(*User)
+	return user, ok
+}
@@ -0,0 +1,142 @@
+// Package httperr gives handlers a way to return typed, JSON-encoded errors
+// instead of calling log.Fatal (which kills the whole server on a single bad
+// request).
+package httperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var validate = validator.New()
+
+// APIError is the JSON shape returned to clients on failure.
+type APIError struct {
+	Code    int         `json:"-"`
+	Message string      `json:"error"`
+	Details interface{} `json:"details,omitempty"`
+
+	// logErr is the underlying error for Internal APIErrors. It is never
+	// serialized to the client (unexported) but is logged by Adapt so the
+	// correlation ID still maps to the real cause server-side.
+	logErr error
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// NotFound builds a 404 APIError.
+func NotFound(message string) *APIError {
+	return &APIError{Code: http.StatusNotFound, Message: message}
+}
+
+// BadRequest builds a 400 APIError, optionally carrying field-level details.
+func BadRequest(message string, details interface{}) *APIError {
+	return &APIError{Code: http.StatusBadRequest, Message: message, Details: details}
+}
+
+// UnprocessableEntity builds a 422 APIError, typically carrying a
+// field-name-to-reason map from a failed validation pass.
+func UnprocessableEntity(message string, details interface{}) *APIError {
+	return &APIError{Code: http.StatusUnprocessableEntity, Message: message, Details: details}
+}
+
+// Unauthorized builds a 401 APIError.
+func Unauthorized(message string) *APIError {
+	return &APIError{Code: http.StatusUnauthorized, Message: message}
+}
+
+// Internal builds a 500 APIError wrapping an unexpected error. The error is
+// never exposed to the client (it may contain raw DB errors, query
+// fragments, etc.) — it's only available to Adapt for server-side logging.
+func Internal(err error) *APIError {
+	return &APIError{Code: http.StatusInternalServerError, Message: "internal server error", logErr: err}
+}
+
+// FromDBError classifies a database error into the matching APIError,
+// mapping "not found" into 404 and everything else into 500.
+func FromDBError(err error) *APIError {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NotFound("resource not found")
+	}
+	return Internal(err)
+}
+
+// HandlerFunc is an HTTP handler that returns an APIError instead of writing
+// one directly, so error handling stays in one place.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) *APIError
+
+// Adapt turns a HandlerFunc into a standard http.HandlerFunc, writing the
+// APIError as JSON and logging it with the request's correlation ID instead
+// of calling log.Fatal.
+func Adapt(h HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if apiErr := h(w, r); apiErr != nil {
+			if apiErr.logErr != nil {
+				log.Printf("request_id=%s error=%q cause=%v", RequestID(r.Context()), apiErr.Message, apiErr.logErr)
+			} else {
+				log.Printf("request_id=%s error=%q details=%v", RequestID(r.Context()), apiErr.Message, apiErr.Details)
+			}
+			w.WriteHeader(apiErr.Code)
+			json.NewEncoder(w).Encode(apiErr)
+		}
+	}
+}
+
+type contextKey string
+
+const requestIDKey contextKey = "requestID"
+
+// WithRequestID assigns a correlation ID to every incoming request, echoing
+// it back on the X-Request-Id response header.
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if id == "" {
+			id = uuid.NewString()
+		}
+		w.Header().Set("X-Request-Id", id)
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestID returns the correlation ID stashed by WithRequestID, or "" if
+// none is present.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// BindJSON decodes the request body into v, rejecting unknown fields, then
+// runs struct validation tags over it. Decode failures become 400s;
+// validation failures become 422s with a field-name-to-reason map.
+func BindJSON(r *http.Request, v interface{}) *APIError {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return BadRequest("invalid JSON body", err.Error())
+	}
+
+	if err := validate.Struct(v); err != nil {
+		validationErrs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return Internal(err)
+		}
+		fields := make(map[string]string, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields[fe.Field()] = fe.Tag()
+		}
+		return UnprocessableEntity("validation failed", fields)
+	}
+
+	return nil
+}
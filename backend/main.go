@@ -1,52 +1,155 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
-	_ "github.com/lib/pq"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/d3sc/fullstack-go-docker/backend/auth"
+	"github.com/d3sc/fullstack-go-docker/backend/httperr"
+	"github.com/d3sc/fullstack-go-docker/backend/models"
+	"github.com/d3sc/fullstack-go-docker/backend/repository"
+	"github.com/d3sc/fullstack-go-docker/backend/ws"
 )
 
-type User struct {
-	Id    int    `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-}
+const shutdownTimeout = 30 * time.Second
 
 // main function
 func main() {
+	if err := auth.ValidateConfig(); err != nil {
+		log.Fatal(err)
+	}
+
 	// connect database
 
-	db, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	db, err := gorm.Open(postgres.Open(os.Getenv("DATABASE_URL")), &gorm.Config{})
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	defer db.Close()
-
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS users (id SERIAL PRIMARY KEY, name TEXT, email TEXT)")
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}); err != nil {
+		log.Fatal(err)
+	}
 
+	sqlDB, err := db.DB()
 	if err != nil {
 		log.Fatal(err)
 	}
+	configureConnectionPool(sqlDB)
+
+	userRepo := repository.NewUserRepository(db)
+	postRepo := repository.NewPostRepository(db)
+
+	hub := ws.NewHub()
+	go hub.Run()
+	go func() {
+		if err := ws.ListenNotify(hub, os.Getenv("DATABASE_URL")); err != nil {
+			log.Printf("ws: listen/notify fan-in stopped: %v", err)
+		}
+	}()
 
 	router := mux.NewRouter()
 
-	router.HandleFunc("/api/go/users", getUsers(db)).Methods("GET")
-	router.HandleFunc("/api/go/users", createUser(db)).Methods("POST")
-	router.HandleFunc("/api/go/users/{id}", getUser(db)).Methods("GET")
-	router.HandleFunc("/api/go/users/{id}", updateUser(db)).Methods("PUT")
-	router.HandleFunc("/api/go/users/{id}", deleteUser(db)).Methods("DELETE")
+	router.HandleFunc("/healthz", healthz).Methods("GET")
+	router.HandleFunc("/readyz", readyz(sqlDB)).Methods("GET")
+
+	router.HandleFunc("/api/go/auth/register", httperr.Adapt(registerUser(userRepo))).Methods("POST")
+	router.HandleFunc("/api/go/auth/login", httperr.Adapt(loginUser(userRepo))).Methods("POST")
+
+	router.HandleFunc("/api/go/users", httperr.Adapt(getUsers(userRepo))).Methods("GET")
+	router.HandleFunc("/api/go/users", httperr.Adapt(createUser(userRepo, db))).Methods("POST")
+	router.HandleFunc("/api/go/users/events", ws.Handler(hub)).Methods("GET")
+	router.HandleFunc("/api/go/users/{id}", httperr.Adapt(getUser(userRepo))).Methods("GET")
+	router.HandleFunc("/api/go/users/{id}/posts", httperr.Adapt(getUserPosts(postRepo))).Methods("GET")
+	router.Handle("/api/go/users/{id}", auth.RequireAuth(auth.RequireRole("admin")(httperr.Adapt(updateUser(userRepo, db))))).Methods("PUT")
+	router.Handle("/api/go/users/{id}", auth.RequireAuth(auth.RequireRole("admin")(httperr.Adapt(deleteUser(userRepo, db))))).Methods("DELETE")
 
 	// set up middleware
-	enhancedRouter := enableCORS(JsonContentTypeMiddleware(router))
+	enhancedRouter := httperr.WithRequestID(enableCORS(JsonContentTypeMiddleware(router)))
+
+	srv := &http.Server{
+		Addr:    ":8000",
+		Handler: enhancedRouter,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("server shutdown did not complete cleanly: %v", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("error closing database connection: %v", err)
+	}
+}
+
+// configureConnectionPool applies pool limits from env vars, falling back
+// to sane defaults for a small API service.
+func configureConnectionPool(sqlDB *sql.DB) {
+	maxOpen := envInt("DB_MAX_OPEN_CONNS", 25)
+	maxIdle := envInt("DB_MAX_IDLE_CONNS", 25)
+	connLifetime := envDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute)
+
+	sqlDB.SetMaxOpenConns(maxOpen)
+	sqlDB.SetMaxIdleConns(maxIdle)
+	sqlDB.SetConnMaxLifetime(connLifetime)
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
 
-	log.Fatal(http.ListenAndServe(":8000", enhancedRouter))
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// healthz is a liveness probe: if the process can answer HTTP, it's alive.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// readyz is a readiness probe: the service is only ready once it can reach
+// the database.
+func readyz(sqlDB *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := sqlDB.PingContext(r.Context()); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"status": "not ready"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	}
 }
 
 // create middleware func
@@ -57,7 +160,7 @@ func enableCORS(next http.Handler) http.Handler {
 		// set CORS header
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -78,150 +181,265 @@ func JsonContentTypeMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// get all users
-func getUsers(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// get rows of all user
-		rows, err := db.Query("SELECT * FROM users")
+const (
+	defaultUsersLimit = 20
+	maxUsersLimit     = 100
+)
+
+// get all users, paginated/filtered/sorted via query params
+func getUsers(repo *repository.UserRepository) httperr.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *httperr.APIError {
+		params := parseListUsersParams(r)
+
+		users, total, err := repo.List(r.Context(), params)
 		if err != nil {
-			log.Fatal(err)
+			return httperr.FromDBError(err)
 		}
 
-		// defer rows.Close() digunakan untuk menutup koneksi database setelah fungsi selesai dieksekusi
-		// ini penting untuk mencegah memory leak dan memastikan resource database dilepaskan dengan benar
-		defer rows.Close()
+		// return json to web
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": users,
+			"meta": map[string]interface{}{
+				"total":  total,
+				"limit":  params.Limit,
+				"offset": params.Offset,
+			},
+		})
+		return nil
+	}
+}
 
-		users := []User{} /* Create array of users */
+// parseListUsersParams reads ?limit=, ?offset= (or ?page=/?page_size=),
+// ?sort=, and ?q= off the request, clamping limit to [1, maxUsersLimit].
+func parseListUsersParams(r *http.Request) repository.ListParams {
+	q := r.URL.Query()
 
-		// check all user is available (no error)
-		for rows.Next() {
-			var user User
-			// scan data per user
-			// method Scan() digunakan untuk memindahkan nilai dari hasil query ke dalam variabel yang ditentukan
-			// dalam kasus ini memindahkan nilai id, name, dan email dari hasil query ke dalam struct User
-			if err := rows.Scan(&user.Id, &user.Name, &user.Email); err != nil {
-				log.Fatal(err)
+	limit := defaultUsersLimit
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if limit > maxUsersLimit {
+		limit = maxUsersLimit
+	}
+
+	offset := 0
+	if v, err := strconv.Atoi(q.Get("offset")); err == nil && v >= 0 {
+		offset = v
+	} else if page, err := strconv.Atoi(q.Get("page")); err == nil && page > 0 {
+		pageSize := limit
+		if v, err := strconv.Atoi(q.Get("page_size")); err == nil && v > 0 {
+			pageSize = v
+			if pageSize > maxUsersLimit {
+				pageSize = maxUsersLimit
 			}
-			// append every single data of user in var users
-			users = append(users, user)
-		}
-		// check err
-		if err := rows.Err(); err != nil {
-			log.Fatal(err)
+			limit = pageSize
 		}
+		offset = (page - 1) * pageSize
+	}
 
-		// return json to web
-		json.NewEncoder(w).Encode(users)
+	var sort []string
+	if s := q.Get("sort"); s != "" {
+		sort = strings.Split(s, ",")
+	}
+
+	return repository.ListParams{
+		Limit:  limit,
+		Offset: offset,
+		Sort:   sort,
+		Query:  q.Get("q"),
 	}
 }
 
 // get user by id
-func getUser(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+func getUser(repo *repository.UserRepository) httperr.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *httperr.APIError {
 		// get url params id
 		vars := mux.Vars(r)
 		id := vars["id"]
 
-		// get 1 row user in database
-		var user User
-		// method Scan() digunakan untuk memindahkan nilai dari hasil query ke dalam variabel yang ditentukan
-		// dalam kasus ini memindahkan nilai id, name, dan email dari hasil query ke dalam struct User
-		err := db.QueryRow("SELECT * FROM users WHERE id = $1", id).Scan(&user.Id, &user.Name, &user.Email)
-
-		// if data rows not found
+		user, err := repo.Get(r.Context(), id)
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			return
+			return httperr.FromDBError(err)
 		}
 
 		// return json to web
 		json.NewEncoder(w).Encode(user)
+		return nil
 	}
 }
 
-// create user
-func createUser(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// get request body from post
-		var user User
-		json.NewDecoder(r.Body).Decode(&user)
+// get posts belonging to a user
+func getUserPosts(repo *repository.PostRepository) httperr.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *httperr.APIError {
+		vars := mux.Vars(r)
+		id := vars["id"]
 
-		// method Scan() digunakan untuk memindahkan nilai dari hasil query ke dalam variabel yang ditentukan
-		// dalam kasus ini memindahkan nilai id, name, dan email dari hasil query ke dalam struct User
-		err := db.QueryRow("INSERT INTO users (name, email) values ($1, $2) RETURNING id", user.Name, user.Email).Scan(&user.Id)
+		posts, err := repo.ListByUser(r.Context(), id)
+		if err != nil {
+			return httperr.FromDBError(err)
+		}
 
-		// cehck err
+		json.NewEncoder(w).Encode(posts)
+		return nil
+	}
+}
+
+// registerUser creates a new account with a bcrypt-hashed password and the
+// default "user" role, then returns a signed JWT.
+func registerUser(repo *repository.UserRepository) httperr.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *httperr.APIError {
+		var req struct {
+			Name     string `json:"name" validate:"required,min=2,max=100"`
+			Email    string `json:"email" validate:"required,email"`
+			Password string `json:"password" validate:"required,min=8"`
+		}
+		if apiErr := httperr.BindJSON(r, &req); apiErr != nil {
+			return apiErr
+		}
+
+		hash, err := auth.HashPassword(req.Password)
 		if err != nil {
-			log.Fatal(err)
+			return httperr.Internal(err)
 		}
 
+		user := models.User{
+			Name:         req.Name,
+			Email:        req.Email,
+			PasswordHash: hash,
+			Role:         "user",
+		}
+		if err := repo.Create(r.Context(), &user); err != nil {
+			return httperr.FromDBError(err)
+		}
+
+		token, err := auth.IssueToken(user.Id, user.Email, user.Role)
+		if err != nil {
+			return httperr.Internal(err)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"user": user, "token": token})
+		return nil
+	}
+}
+
+// loginUser verifies email/password and returns a signed JWT on success.
+func loginUser(repo *repository.UserRepository) httperr.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *httperr.APIError {
+		var req struct {
+			Email    string `json:"email" validate:"required,email"`
+			Password string `json:"password" validate:"required"`
+		}
+		if apiErr := httperr.BindJSON(r, &req); apiErr != nil {
+			return apiErr
+		}
+
+		user, err := repo.GetByEmail(r.Context(), req.Email)
+		if err != nil || !auth.CheckPassword(user.PasswordHash, req.Password) {
+			return httperr.Unauthorized("invalid email or password")
+		}
+
+		token, err := auth.IssueToken(user.Id, user.Email, user.Role)
+		if err != nil {
+			return httperr.Internal(err)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"user": user, "token": token})
+		return nil
+	}
+}
+
+// create user. Bound into a restricted DTO (not models.User directly) so a
+// caller can't set role or password_hash through this endpoint.
+func createUser(repo *repository.UserRepository, db *gorm.DB) httperr.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *httperr.APIError {
+		var req struct {
+			Name  string `json:"name" validate:"required,min=2,max=100"`
+			Email string `json:"email" validate:"required,email"`
+		}
+		if apiErr := httperr.BindJSON(r, &req); apiErr != nil {
+			return apiErr
+		}
+
+		user := models.User{Name: req.Name, Email: req.Email, Role: "user"}
+		if err := repo.Create(r.Context(), &user); err != nil {
+			return httperr.FromDBError(err)
+		}
+
+		broadcastUserEvent(db, "created", user)
+
 		// return json
 		json.NewEncoder(w).Encode(user)
+		return nil
 	}
 }
 
 // update user
-func updateUser(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+func updateUser(repo *repository.UserRepository, db *gorm.DB) httperr.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *httperr.APIError {
 		// get url paramter id
 		vars := mux.Vars(r)
 		id := vars["id"]
 
-		// get request body
-		var user User
-		json.NewDecoder(r.Body).Decode(&user)
-
-		//* PENTING! (IMPORTANT!)
-		//* Gunakan tool yang tepat untuk pekerjaan yang tepat
-		//* Query() untuk SELECT dengan banyak baris
-		//* QueryRow() untuk SELECT satu baris atau operasi dengan RETURNING
-		//* Exec() untuk operasi non-SELECT tanpa RETURNING
+		// get request body. Bound into a restricted DTO (not models.User
+		// directly) so a caller can't set role or password_hash through this
+		// endpoint.
+		var req struct {
+			Name  string `json:"name" validate:"required,min=2,max=100"`
+			Email string `json:"email" validate:"required,email"`
+		}
+		if apiErr := httperr.BindJSON(r, &req); apiErr != nil {
+			return apiErr
+		}
 
-		// exec update user
-		_, err := db.Exec("UPDATE users SET name = $1, email = $2 WHERE id = $3 RETURNING id", user.Name, user.Email, id)
-		if err != nil {
-			log.Fatal(err)
+		user := models.User{Name: req.Name, Email: req.Email}
+		if err := repo.Update(r.Context(), id, &user); err != nil {
+			return httperr.FromDBError(err)
 		}
 
 		// query check for updated user are exist?
-		var updatedUser User
-		err = db.QueryRow("SELECT * FROM users WHERE id = $1", id).Scan(&updatedUser.Id, &updatedUser.Name, &updatedUser.Email)
+		updatedUser, err := repo.Get(r.Context(), id)
 		if err != nil {
-			log.Fatal(err)
+			return httperr.FromDBError(err)
 		}
 
-		json.NewEncoder(w).Encode(updatedUser)
+		broadcastUserEvent(db, "updated", updatedUser)
 
+		json.NewEncoder(w).Encode(updatedUser)
+		return nil
 	}
 }
 
 // delete user
-func deleteUser(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+func deleteUser(repo *repository.UserRepository, db *gorm.DB) httperr.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *httperr.APIError {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
-		// Gunakan Exec() karena tidak memerlukan data yang dikembalikan
-		result, err := db.Exec("DELETE FROM users WHERE id = $1", id)
+		user, err := repo.Get(r.Context(), id)
 		if err != nil {
-			log.Fatal(err)
+			return httperr.FromDBError(err)
 		}
 
-		// Periksa apakah ada baris yang terpengaruh
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			log.Fatal(err)
+		if err := repo.Delete(r.Context(), id); err != nil {
+			return httperr.FromDBError(err)
 		}
 
-		if rowsAffected == 0 {
-			// Jika tidak ada baris yang dihapus, berarti user tidak ditemukan
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]string{"message": "User tidak ditemukan"})
-			return
-		}
+		broadcastUserEvent(db, "deleted", user)
 
 		// Kirim respons sukses
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{"message": "User berhasil dihapus"})
+		return nil
+	}
+}
+
+// broadcastUserEvent publishes via pg_notify; every replica's own
+// ListenNotify loop (including this one) picks it up and fans it out to its
+// local hub, so we don't also call hub.Broadcast directly here (that would
+// double-deliver to this replica's clients).
+func broadcastUserEvent(db *gorm.DB, eventType string, user models.User) {
+	event := ws.Event{Type: eventType, User: user}
+	if err := ws.Notify(db, event); err != nil {
+		log.Printf("ws: pg_notify failed: %v", err)
 	}
 }
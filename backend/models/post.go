@@ -0,0 +1,9 @@
+package models
+
+// Post belongs to a single User.
+type Post struct {
+	Id     int    `json:"id" gorm:"primaryKey"`
+	UserID int    `json:"user_id"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
@@ -0,0 +1,13 @@
+// Package models holds the GORM-mapped domain types shared by the
+// repository layer and the HTTP handlers.
+package models
+
+// User is a registered account.
+type User struct {
+	Id           int    `json:"id" gorm:"primaryKey"`
+	Name         string `json:"name" gorm:"index" validate:"required,min=2,max=100"`
+	Email        string `json:"email" gorm:"uniqueIndex" validate:"required,email"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role" gorm:"default:user"`
+	Posts        []Post `json:"posts,omitempty" gorm:"foreignKey:UserID"`
+}
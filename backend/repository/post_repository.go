@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/d3sc/fullstack-go-docker/backend/models"
+)
+
+// PostRepository provides read access to posts.
+type PostRepository struct {
+	db *gorm.DB
+}
+
+// NewPostRepository builds a PostRepository backed by db.
+func NewPostRepository(db *gorm.DB) *PostRepository {
+	return &PostRepository{db: db}
+}
+
+// ListByUser returns every post belonging to the given user id.
+func (r *PostRepository) ListByUser(ctx context.Context, userID string) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&posts).Error
+	return posts, err
+}
@@ -0,0 +1,117 @@
+// Package repository wraps GORM access to the domain models so handlers
+// don't talk to the database directly.
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/d3sc/fullstack-go-docker/backend/models"
+)
+
+// UserRepository provides CRUD access to users.
+type UserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository builds a UserRepository backed by db.
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// userSortColumns whitelists the columns ?sort= may reference, so it can
+// never be used to inject arbitrary SQL into ORDER BY.
+var userSortColumns = map[string]bool{
+	"id":    true,
+	"name":  true,
+	"email": true,
+}
+
+// ListParams controls pagination, filtering, and sorting for List.
+type ListParams struct {
+	Limit  int
+	Offset int
+	Sort   []string // e.g. []string{"name", "-id"}
+	Query  string    // substring match against name/email
+}
+
+// List returns the users matching params along with the total row count
+// (ignoring limit/offset) for building a pagination envelope. The count and
+// the page are read in the same transaction so the two numbers can't
+// disagree under concurrent writes.
+func (r *UserRepository) List(ctx context.Context, params ListParams) ([]models.User, int64, error) {
+	var (
+		total int64
+		users []models.User
+	)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.User{})
+
+		if params.Query != "" {
+			like := "%" + params.Query + "%"
+			query = query.Where("name ILIKE ? OR email ILIKE ?", like, like)
+		}
+
+		if err := query.Count(&total).Error; err != nil {
+			return err
+		}
+
+		for _, field := range params.Sort {
+			dir := "ASC"
+			col := field
+			if strings.HasPrefix(col, "-") {
+				dir = "DESC"
+				col = col[1:]
+			}
+			if !userSortColumns[col] {
+				continue
+			}
+			query = query.Order(col + " " + dir)
+		}
+
+		return query.Limit(params.Limit).Offset(params.Offset).Find(&users).Error
+	})
+
+	return users, total, err
+}
+
+// Get returns the user with the given id.
+func (r *UserRepository) Get(ctx context.Context, id string) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error
+	return user, err
+}
+
+// GetByEmail returns the user with the given email, used during login.
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, "email = ?", email).Error
+	return user, err
+}
+
+// Create inserts a new user.
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+// Update persists changes to name/email on an existing user.
+func (r *UserRepository) Update(ctx context.Context, id string, user *models.User) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"name": user.Name, "email": user.Email}).Error
+}
+
+// Delete removes the user with the given id, returning gorm.ErrRecordNotFound
+// if no row matched.
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Delete(&models.User{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/d3sc/fullstack-go-docker/backend/models"
+)
+
+func newTestUserRepo(t *testing.T) *UserRepository {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open in-memory sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	return NewUserRepository(db)
+}
+
+func TestUserRepository_CreateGet(t *testing.T) {
+	repo := newTestUserRepo(t)
+	ctx := context.Background()
+
+	user := models.User{Name: "Ada Lovelace", Email: "ada@example.com", Role: "user"}
+	if err := repo.Create(ctx, &user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if user.Id == 0 {
+		t.Fatalf("expected Create to populate Id")
+	}
+
+	got, err := repo.Get(ctx, strconv.Itoa(user.Id))
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Email != user.Email {
+		t.Fatalf("expected email %q, got %q", user.Email, got.Email)
+	}
+}
+
+func TestUserRepository_UpdateDelete(t *testing.T) {
+	repo := newTestUserRepo(t)
+	ctx := context.Background()
+
+	user := models.User{Name: "Grace Hopper", Email: "grace@example.com", Role: "user"}
+	if err := repo.Create(ctx, &user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	id := strconv.Itoa(user.Id)
+	update := models.User{Name: "Grace M. Hopper", Email: "grace.hopper@example.com"}
+	if err := repo.Update(ctx, id, &update); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, err := repo.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get after update: %v", err)
+	}
+	if got.Name != update.Name {
+		t.Fatalf("expected name %q, got %q", update.Name, got.Name)
+	}
+
+	if err := repo.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete(ctx, id); err != gorm.ErrRecordNotFound {
+		t.Fatalf("expected gorm.ErrRecordNotFound on second delete, got %v", err)
+	}
+}
+
+func TestUserRepository_ListPagination(t *testing.T) {
+	repo := newTestUserRepo(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		u := models.User{Name: name, Email: name + "@example.com", Role: "user"}
+		if err := repo.Create(ctx, &u); err != nil {
+			t.Fatalf("Create %s: %v", name, err)
+		}
+	}
+
+	users, total, err := repo.List(ctx, ListParams{Limit: 2, Offset: 0, Sort: []string{"name"}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 3 {
+		t.Fatalf("expected total 3, got %d", total)
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users on first page, got %d", len(users))
+	}
+	if users[0].Name != "Alice" {
+		t.Fatalf("expected sorted first result Alice, got %s", users[0].Name)
+	}
+}
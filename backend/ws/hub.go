@@ -0,0 +1,161 @@
+// Package ws streams user change events to subscribed clients over
+// WebSocket, backed by an in-process hub.
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/d3sc/fullstack-go-docker/backend/models"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	clientSendSize = 16
+)
+
+// Event is broadcast to every subscriber whenever a user is created,
+// updated, or deleted.
+type Event struct {
+	Type string      `json:"type"` // "created", "updated", or "deleted"
+	User models.User `json:"user"`
+}
+
+// Hub tracks connected clients and fans events out to all of them.
+type Hub struct {
+	clients    map[*client]bool
+	broadcast  chan Event
+	register   chan *client
+	unregister chan *client
+}
+
+// NewHub builds an idle Hub. Call Run to start it.
+func NewHub() *Hub {
+	return &Hub{
+		clients:    make(map[*client]bool),
+		broadcast:  make(chan Event),
+		register:   make(chan *client),
+		unregister: make(chan *client),
+	}
+}
+
+// Run processes registrations and broadcasts until ctx-less shutdown (the
+// process exiting). Call it in its own goroutine.
+func (h *Hub) Run() {
+	for {
+		select {
+		case c := <-h.register:
+			h.clients[c] = true
+
+		case c := <-h.unregister:
+			if _, ok := h.clients[c]; ok {
+				delete(h.clients, c)
+				close(c.send)
+			}
+
+		case event := <-h.broadcast:
+			payload, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("ws: failed to marshal event: %v", err)
+				continue
+			}
+			for c := range h.clients {
+				select {
+				case c.send <- payload:
+				default:
+					// Slow consumer: drop it instead of blocking the hub.
+					delete(h.clients, c)
+					close(c.send)
+				}
+			}
+		}
+	}
+}
+
+// Broadcast queues event for delivery to every connected client.
+func (h *Hub) Broadcast(event Event) {
+	h.broadcast <- event
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Handler upgrades the request to a WebSocket and streams events from hub
+// until the client disconnects.
+func Handler(hub *Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("ws: upgrade failed: %v", err)
+			return
+		}
+
+		c := &client{hub: hub, conn: conn, send: make(chan []byte, clientSendSize)}
+		hub.register <- c
+
+		go c.writePump()
+		go c.readPump()
+	}
+}
+
+type client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	send chan []byte
+}
+
+// readPump only exists to notice the client going away and to answer
+// keepalive pings; subscribers don't send us anything meaningful.
+func (c *client) readPump() {
+	defer func() {
+		c.hub.unregister <- c
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const notifyChannel = "user_events"
+
+// ListenNotify subscribes to the Postgres "user_events" channel and
+// re-broadcasts whatever arrives on hub, so every API replica's WebSocket
+// clients see events published by any of the others. Run it in its own
+// goroutine; it returns an error only if the initial Listen call fails, and
+// otherwise blocks for as long as the listener's notification channel stays
+// open (the listener reconnects on its own after connection loss).
+func ListenNotify(hub *Hub, dsn string) error {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("ws: listener event error: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(notifyChannel); err != nil {
+		return err
+	}
+
+	for notification := range listener.Notify {
+		if notification == nil {
+			continue // re-connected; nothing to replay
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+			log.Printf("ws: failed to unmarshal notification: %v", err)
+			continue
+		}
+		hub.Broadcast(event)
+	}
+
+	return nil
+}
+
+// Notify publishes event via pg_notify on the "user_events" channel so
+// other replicas' ListenNotify goroutines pick it up too.
+func Notify(db *gorm.DB, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return db.Exec("SELECT pg_notify(?, ?)", notifyChannel, string(payload)).Error
+}